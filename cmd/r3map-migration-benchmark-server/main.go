@@ -11,6 +11,7 @@ import (
 	"github.com/pojntfx/go-nbd/pkg/backend"
 	v1frpc "github.com/pojntfx/r3map/pkg/api/frpc/v1"
 	v1proto "github.com/pojntfx/r3map/pkg/api/proto/v1"
+	"github.com/pojntfx/r3map/pkg/chunks"
 	"github.com/pojntfx/r3map/pkg/migration"
 	"github.com/pojntfx/r3map/pkg/services"
 	"github.com/pojntfx/r3map/pkg/utils"
@@ -28,9 +29,17 @@ func main() {
 	enableGrpc := flag.Bool("grpc", false, "Whether to use gRPC instead of Dudirekta")
 	enableDrpc := flag.Bool("drpc", false, "Whether to use DRPC instead of Dudirekta")
 	enableFrpc := flag.Bool("frpc", false, "Whether to use fRPC instead of Dudirekta")
+	enableJSONRPC := flag.Bool("jsonrpc", false, "Whether to use JSON-RPC instead of Dudirekta")
+	jsonRPCWebSocket := flag.Bool("jsonrpc-websocket", false, "When using JSON-RPC, serve over WebSocket instead of plain TCP")
+	codecName := flag.String("codec", "", "Compression codec to frame served chunks with (\"\", \"zstd\" or \"s2\")")
 
 	flag.Parse()
 
+	codec, err := chunks.CodecByName(*codecName)
+	if err != nil {
+		panic(err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -69,6 +78,9 @@ func main() {
 		defer seeder.Close()
 
 		svc = s
+		if codec != nil {
+			svc.Configure(services.WithCodec(codec, *chunkSize))
+		}
 
 		log.Println("Connected to slice")
 	} else {
@@ -103,6 +115,9 @@ func main() {
 		defer seeder.Close()
 
 		svc = s
+		if codec != nil {
+			svc.Configure(services.WithCodec(codec, *chunkSize))
+		}
 
 		log.Println("Connected on", deviceFile.Name())
 	}
@@ -143,6 +158,31 @@ func main() {
 					errs <- err
 				}
 
+				return
+			}
+		}()
+	} else if *enableJSONRPC {
+		server := services.NewSeederJSONRPC(svc)
+
+		var listen func(laddr string) error
+		if *jsonRPCWebSocket {
+			listen = server.ListenWebSocket
+		} else {
+			listen = server.ListenTCP
+		}
+
+		if err := listen(*laddr); err != nil {
+			panic(err)
+		}
+
+		log.Println("Listening on", *laddr)
+
+		go func() {
+			if err := server.Wait(); err != nil {
+				if !utils.IsClosedErr(err) {
+					errs <- err
+				}
+
 				return
 			}
 		}()
@@ -236,4 +276,4 @@ func main() {
 			panic(err)
 		}
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,244 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// KVEvent describes a single change observed on a watched key.
+type KVEvent struct {
+	Key   string
+	Value []byte
+}
+
+// KVStore is the minimal interface a distributed key/value store (etcd,
+// Redis, Consul, ...) must provide to back a KVBackend. It is intentionally
+// small (a libkv-style abstraction) so that any of those stores can be
+// wrapped without pulling a specific client into this package.
+type KVStore interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, value []byte) error
+}
+
+// KVBatcher may be implemented by a KVStore that can group multiple puts
+// into a single transaction. KVBackend uses it to batch multi-chunk writes
+// where the store supports it, falling back to one Put per chunk otherwise.
+type KVBatcher interface {
+	NewBatch() KVBatch
+}
+
+// KVBatch accumulates puts for a single transaction.
+type KVBatch interface {
+	Put(key string, value []byte)
+	Commit(ctx context.Context) error
+}
+
+// KVWatcher may be implemented by a KVStore that can notify callers about
+// changes made to keys under a prefix by another process, e.g. a seeder
+// pushing invalidations to connected pullers when a chunk changes remotely.
+type KVWatcher interface {
+	Watch(ctx context.Context, prefix string) (<-chan KVEvent, error)
+}
+
+type KVBackendHooks struct {
+	// OnInvalidate is called with the offset of a chunk whenever the
+	// underlying store reports (via KVWatcher) that it changed remotely.
+	OnInvalidate func(off int64) error
+}
+
+// KVBackend stores chunks as key/value pairs in an external KV store, keyed
+// by <namespace>/<chunkIndex>. It implements go-nbd's backend.Backend
+// interface (ReadAt/WriteAt/Size/Sync), giving migrations a shared,
+// persistent, highly-available source of truth instead of requiring a
+// single seeder process to stay alive.
+type KVBackend struct {
+	ctx context.Context
+
+	store     KVStore
+	namespace string
+
+	chunkSize  int64
+	chunkCount int64
+
+	hooks *KVBackendHooks
+
+	wg   sync.WaitGroup
+	errs chan error
+}
+
+func NewKVBackend(
+	ctx context.Context,
+
+	store KVStore,
+	namespace string,
+
+	chunkSize int64,
+	chunkCount int64,
+
+	hooks *KVBackendHooks,
+) *KVBackend {
+	if hooks == nil {
+		hooks = &KVBackendHooks{}
+	}
+
+	return &KVBackend{
+		ctx: ctx,
+
+		store:     store,
+		namespace: namespace,
+
+		chunkSize:  chunkSize,
+		chunkCount: chunkCount,
+
+		hooks: hooks,
+
+		errs: make(chan error),
+	}
+}
+
+// Open starts watching the backend's namespace for remote invalidations, if
+// the underlying store supports it. It is a no-op otherwise.
+func (b *KVBackend) Open() error {
+	watcher, ok := b.store.(KVWatcher)
+	if !ok {
+		return nil
+	}
+
+	events, err := watcher.Watch(b.ctx, b.namespace+"/")
+	if err != nil {
+		return err
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		for event := range events {
+			off, err := b.keyOffset(event.Key)
+			if err != nil {
+				continue
+			}
+
+			if b.hooks.OnInvalidate != nil {
+				if err := b.hooks.OnInvalidate(off); err != nil {
+					b.errs <- err
+
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (b *KVBackend) Wait() error {
+	for err := range b.errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *KVBackend) Close() error {
+	b.wg.Wait()
+
+	close(b.errs)
+
+	return nil
+}
+
+func (b *KVBackend) key(chunkOff int64) string {
+	return fmt.Sprintf("%v/%v", b.namespace, chunkOff/b.chunkSize)
+}
+
+func (b *KVBackend) keyOffset(key string) (int64, error) {
+	var idx int64
+	if _, err := fmt.Sscanf(key, b.namespace+"/%d", &idx); err != nil {
+		return 0, err
+	}
+
+	return idx * b.chunkSize, nil
+}
+
+func (b *KVBackend) chunkBounds(off int64, length int) (start, end int64) {
+	start = (off / b.chunkSize) * b.chunkSize
+	end = (((off + int64(length) - 1) / b.chunkSize) + 1) * b.chunkSize
+
+	return start, end
+}
+
+func (b *KVBackend) ReadAt(p []byte, off int64) (int, error) {
+	start, end := b.chunkBounds(off, len(p))
+
+	var read int
+	for chunkOff := start; chunkOff < end; chunkOff += b.chunkSize {
+		value, err := b.store.Get(b.ctx, b.key(chunkOff))
+		if err != nil {
+			return read, err
+		}
+
+		chunk := make([]byte, b.chunkSize)
+		copy(chunk, value)
+
+		read += copy(p[read:], chunk[max64(0, off-chunkOff):])
+	}
+
+	return read, nil
+}
+
+func (b *KVBackend) WriteAt(p []byte, off int64) (int, error) {
+	start, end := b.chunkBounds(off, len(p))
+
+	var batch KVBatch
+	if batcher, ok := b.store.(KVBatcher); ok {
+		batch = batcher.NewBatch()
+	}
+
+	var written int
+	for chunkOff := start; chunkOff < end; chunkOff += b.chunkSize {
+		chunk := make([]byte, b.chunkSize)
+
+		existing, err := b.store.Get(b.ctx, b.key(chunkOff))
+		if err != nil {
+			return written, err
+		}
+		copy(chunk, existing)
+
+		n := copy(chunk[max64(0, off-chunkOff):], p[written:])
+		written += n
+
+		if batch != nil {
+			batch.Put(b.key(chunkOff), chunk)
+		} else if err := b.store.Put(b.ctx, b.key(chunkOff), chunk); err != nil {
+			return written, err
+		}
+	}
+
+	if batch != nil {
+		if err := batch.Commit(b.ctx); err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+func (b *KVBackend) Size() (int64, error) {
+	return b.chunkSize * b.chunkCount, nil
+}
+
+func (b *KVBackend) Sync() error {
+	return nil
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,196 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryKVStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemoryKVStore() *memoryKVStore {
+	return &memoryKVStore{data: map[string][]byte{}}
+}
+
+func (s *memoryKVStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data[key], nil
+}
+
+func (s *memoryKVStore) Put(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[key] = append([]byte{}, value...)
+
+	return nil
+}
+
+func TestKVBackendReadWriteAt(t *testing.T) {
+	store := newMemoryKVStore()
+
+	b := NewKVBackend(context.Background(), store, "test", 4, 2, nil)
+
+	if _, err := b.WriteAt([]byte("testdata"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]byte, 8)
+	if _, err := b.ReadAt(out, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(out, []byte("testdata")) {
+		t.Errorf("data read did not match expected. got %v, want %v", out, []byte("testdata"))
+	}
+
+	size, err := b.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != 8 {
+		t.Errorf("size did not match expected. got %v, want %v", size, 8)
+	}
+}
+
+// batchingKVStore additionally implements KVBatcher, tracking how many
+// batches were committed so a test can assert WriteAt used it instead of
+// falling back to one Put per chunk.
+type batchingKVStore struct {
+	*memoryKVStore
+
+	mu      sync.Mutex
+	commits int
+}
+
+func newBatchingKVStore() *batchingKVStore {
+	return &batchingKVStore{memoryKVStore: newMemoryKVStore()}
+}
+
+type memoryKVBatch struct {
+	store *batchingKVStore
+	puts  map[string][]byte
+}
+
+func (s *batchingKVStore) NewBatch() KVBatch {
+	return &memoryKVBatch{store: s, puts: map[string][]byte{}}
+}
+
+func (b *memoryKVBatch) Put(key string, value []byte) {
+	b.puts[key] = append([]byte{}, value...)
+}
+
+func (b *memoryKVBatch) Commit(ctx context.Context) error {
+	b.store.mu.Lock()
+	b.store.commits++
+	b.store.mu.Unlock()
+
+	for key, value := range b.puts {
+		if err := b.store.Put(ctx, key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestKVBackendBatchesMultiChunkWrites(t *testing.T) {
+	store := newBatchingKVStore()
+
+	b := NewKVBackend(context.Background(), store, "test", 4, 2, nil)
+
+	if _, err := b.WriteAt([]byte("testdata"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.commits != 1 {
+		t.Errorf("commits did not match expected. got %v, want %v", store.commits, 1)
+	}
+
+	out := make([]byte, 8)
+	if _, err := b.ReadAt(out, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(out, []byte("testdata")) {
+		t.Errorf("data read did not match expected. got %v, want %v", out, []byte("testdata"))
+	}
+}
+
+// watchingKVStore additionally implements KVWatcher, forwarding whatever is
+// sent on events to the channel KVBackend.Open subscribes to.
+type watchingKVStore struct {
+	*memoryKVStore
+
+	events chan KVEvent
+}
+
+func newWatchingKVStore() *watchingKVStore {
+	return &watchingKVStore{memoryKVStore: newMemoryKVStore(), events: make(chan KVEvent, 1)}
+}
+
+func (s *watchingKVStore) Watch(ctx context.Context, prefix string) (<-chan KVEvent, error) {
+	return s.events, nil
+}
+
+func TestKVBackendPropagatesWatchedInvalidations(t *testing.T) {
+	store := newWatchingKVStore()
+
+	invalidated := make(chan int64, 1)
+
+	b := NewKVBackend(context.Background(), store, "test", 4, 2, &KVBackendHooks{
+		OnInvalidate: func(off int64) error {
+			invalidated <- off
+
+			return nil
+		},
+	})
+
+	if err := b.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	store.events <- KVEvent{Key: "test/1"}
+
+	select {
+	case off := <-invalidated:
+		if off != 4 {
+			t.Errorf("invalidated offset did not match expected. got %v, want %v", off, 4)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for invalidation")
+	}
+
+	close(store.events)
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKVBackendUnalignedWrite(t *testing.T) {
+	store := newMemoryKVStore()
+
+	b := NewKVBackend(context.Background(), store, "test", 4, 4, nil)
+
+	if _, err := b.WriteAt([]byte("ab"), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]byte, 4)
+	if _, err := b.ReadAt(out, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(out, []byte{0, 'a', 'b', 0}) {
+		t.Errorf("data read did not match expected. got %v, want %v", out, []byte{0, 'a', 'b', 0})
+	}
+}
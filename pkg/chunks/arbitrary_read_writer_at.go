@@ -0,0 +1,79 @@
+package chunks
+
+// ArbitraryReadWriterAt allows reads and writes at arbitrary, non-chunk
+// aligned offsets and lengths on top of a SyncedReadWriterAt by splitting
+// them into a series of whole-chunk read-modify-write operations. Since it
+// sits directly below the exposed block device, its reads are the guest's
+// actual faults, as opposed to a Puller's own background prefetching.
+type ArbitraryReadWriterAt struct {
+	srw *SyncedReadWriterAt
+
+	chunkSize int64
+
+	onAccess func(off int64)
+}
+
+func NewArbitraryReadWriterAt(srw *SyncedReadWriterAt, chunkSize int64) *ArbitraryReadWriterAt {
+	return &ArbitraryReadWriterAt{
+		srw: srw,
+
+		chunkSize: chunkSize,
+	}
+}
+
+// OnAccess registers fn to be called, in order, with the chunk offset of
+// every guest read. A Puller's RecordAccess can be passed directly to feed
+// its Predictor the guest's real access pattern.
+func (a *ArbitraryReadWriterAt) OnAccess(fn func(off int64)) {
+	a.onAccess = fn
+}
+
+func (a *ArbitraryReadWriterAt) ReadAt(p []byte, off int64) (int, error) {
+	var read int
+
+	buf := make([]byte, a.chunkSize)
+
+	for read < len(p) {
+		absOff := off + int64(read)
+		chunkOff := (absOff / a.chunkSize) * a.chunkSize
+		start := int(absOff - chunkOff)
+
+		if a.onAccess != nil {
+			a.onAccess(chunkOff)
+		}
+
+		if _, err := a.srw.ReadAt(buf, chunkOff); err != nil {
+			return read, err
+		}
+
+		read += copy(p[read:], buf[start:])
+	}
+
+	return read, nil
+}
+
+func (a *ArbitraryReadWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	var written int
+
+	buf := make([]byte, a.chunkSize)
+
+	for written < len(p) {
+		absOff := off + int64(written)
+		chunkOff := (absOff / a.chunkSize) * a.chunkSize
+		start := int(absOff - chunkOff)
+
+		if _, err := a.srw.ReadAt(buf, chunkOff); err != nil {
+			return written, err
+		}
+
+		n := copy(buf[start:], p[written:])
+
+		if _, err := a.srw.WriteAt(buf, chunkOff); err != nil {
+			return written, err
+		}
+
+		written += n
+	}
+
+	return written, nil
+}
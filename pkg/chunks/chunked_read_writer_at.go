@@ -0,0 +1,45 @@
+package chunks
+
+import "io"
+
+// ReaderWriterAt is the minimal storage interface a ChunkedReadWriterAt
+// wraps. *os.File and go-nbd's backend.Backend both satisfy it.
+type ReaderWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// ChunkedReadWriterAt addresses an underlying ReaderWriterAt in terms of a
+// fixed chunk size, which is the unit that the rest of this package (the
+// puller, the synced and arbitrary read/writers) operates on.
+type ChunkedReadWriterAt struct {
+	backend ReaderWriterAt
+
+	chunkSize  int64
+	chunkCount int64
+}
+
+func NewChunkedReadWriterAt(backend ReaderWriterAt, chunkSize int64, chunkCount int64) *ChunkedReadWriterAt {
+	return &ChunkedReadWriterAt{
+		backend: backend,
+
+		chunkSize:  chunkSize,
+		chunkCount: chunkCount,
+	}
+}
+
+func (c *ChunkedReadWriterAt) ReadAt(p []byte, off int64) (int, error) {
+	return c.backend.ReadAt(p, off)
+}
+
+func (c *ChunkedReadWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return c.backend.WriteAt(p, off)
+}
+
+func (c *ChunkedReadWriterAt) ChunkSize() int64 {
+	return c.chunkSize
+}
+
+func (c *ChunkedReadWriterAt) ChunkCount() int64 {
+	return c.chunkCount
+}
@@ -0,0 +1,178 @@
+package chunks
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CodecID identifies the compression codec a chunk was framed with. It is
+// transmitted as the first byte of every framed chunk, so that a single
+// migration can mix encodings (including raw, uncompressed chunks) and a
+// puller that doesn't negotiate compression at all keeps working against
+// the old, headerless wire format.
+type CodecID byte
+
+const (
+	// CodecRaw marks a chunk that was transmitted uncompressed, either
+	// because no codec was negotiated or because compressing it would not
+	// have made it smaller.
+	CodecRaw CodecID = iota
+	CodecZstd
+	CodecS2
+)
+
+// Codec compresses and decompresses chunk payloads independently of one
+// another, so that a puller can request and apply them out of order.
+type Codec interface {
+	ID() CodecID
+	Compress(raw []byte) ([]byte, error)
+	Decompress(compressed []byte, rawLen int) ([]byte, error)
+}
+
+// NewCodec looks up a Codec by the id a chunk was framed with. This is used
+// to decode chunks a seeder compressed with a codec the puller never
+// constructed itself.
+func NewCodec(id CodecID) (Codec, error) {
+	switch id {
+	case CodecRaw:
+		return rawCodec{}, nil
+	case CodecZstd:
+		return newZstdCodec()
+	case CodecS2:
+		return s2Codec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown chunk codec id %v", id)
+	}
+}
+
+// CodecByName looks up a Codec by the config-friendly name callers (e.g.
+// migration.Options.CompressionCodec, or a -codec CLI flag) configure it
+// with: "" for no compression, "zstd" or "s2".
+func CodecByName(name string) (Codec, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "zstd":
+		return NewCodec(CodecZstd)
+	case "s2":
+		return NewCodec(CodecS2)
+	default:
+		return nil, fmt.Errorf("unknown compression codec %q", name)
+	}
+}
+
+type rawCodec struct{}
+
+func (rawCodec) ID() CodecID { return CodecRaw }
+
+func (rawCodec) Compress(raw []byte) ([]byte, error) { return raw, nil }
+
+func (rawCodec) Decompress(compressed []byte, rawLen int) ([]byte, error) { return compressed, nil }
+
+type zstdCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCodec() (*zstdCodec, error) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &zstdCodec{encoder: encoder, decoder: decoder}, nil
+}
+
+func (c *zstdCodec) ID() CodecID { return CodecZstd }
+
+func (c *zstdCodec) Compress(raw []byte) ([]byte, error) {
+	return c.encoder.EncodeAll(raw, nil), nil
+}
+
+func (c *zstdCodec) Decompress(compressed []byte, rawLen int) ([]byte, error) {
+	return c.decoder.DecodeAll(compressed, make([]byte, 0, rawLen))
+}
+
+type s2Codec struct{}
+
+func (s2Codec) ID() CodecID { return CodecS2 }
+
+func (s2Codec) Compress(raw []byte) ([]byte, error) {
+	return s2.Encode(nil, raw), nil
+}
+
+func (s2Codec) Decompress(compressed []byte, rawLen int) ([]byte, error) {
+	return s2.Decode(make([]byte, rawLen), compressed)
+}
+
+// chunkHeaderLen is the size of the per-chunk framing header: one byte for
+// the codec ID, followed by the little-endian uncompressed length.
+const chunkHeaderLen = 1 + 4
+
+// EncodeChunk frames raw with codec, prefixing it with a small header that
+// lets the receiving side know which codec (if any) was used and how large
+// the decompressed chunk is. If codec is nil, raw is framed as CodecRaw
+// so that compression-unaware callers still produce a well-formed frame. If
+// compressing raw doesn't make it smaller, it is stored as CodecRaw instead,
+// so an incompressible chunk never costs more than its raw size plus the
+// header.
+func EncodeChunk(codec Codec, raw []byte) ([]byte, error) {
+	if codec == nil || codec.ID() == CodecRaw {
+		return encodeRaw(raw), nil
+	}
+
+	compressed, err := codec.Compress(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(compressed) >= len(raw) {
+		return encodeRaw(raw), nil
+	}
+
+	header := make([]byte, chunkHeaderLen)
+	header[0] = byte(codec.ID())
+	binary.LittleEndian.PutUint32(header[1:], uint32(len(raw)))
+
+	return append(header, compressed...), nil
+}
+
+func encodeRaw(raw []byte) []byte {
+	header := make([]byte, chunkHeaderLen, chunkHeaderLen+len(raw))
+	header[0] = byte(CodecRaw)
+	binary.LittleEndian.PutUint32(header[1:], uint32(len(raw)))
+
+	return append(header, raw...)
+}
+
+// DecodeChunk reverses EncodeChunk, returning the original, uncompressed
+// chunk payload.
+func DecodeChunk(framed []byte) ([]byte, error) {
+	if len(framed) < chunkHeaderLen {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	id := CodecID(framed[0])
+	rawLen := int(binary.LittleEndian.Uint32(framed[1:chunkHeaderLen]))
+	payload := framed[chunkHeaderLen:]
+
+	if id == CodecRaw {
+		return payload[:rawLen], nil
+	}
+
+	codec, err := NewCodec(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.Decompress(payload, rawLen)
+}
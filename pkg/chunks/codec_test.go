@@ -0,0 +1,63 @@
+package chunks
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeDecodeChunk(t *testing.T) {
+	zstdCodec, err := newZstdCodec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		codec Codec
+		raw   []byte
+	}{
+		{
+			name:  "No codec",
+			codec: nil,
+			raw:   []byte("hello world"),
+		},
+		{
+			name:  "Zstd codec",
+			codec: zstdCodec,
+			raw:   bytes.Repeat([]byte("a"), 4096),
+		},
+		{
+			name:  "S2 codec",
+			codec: s2Codec{},
+			raw:   bytes.Repeat([]byte("a"), 4096),
+		},
+		{
+			name:  "Incompressible chunk falls back to raw",
+			codec: zstdCodec,
+			raw:   []byte{0x01, 0x02, 0x03},
+		},
+		{
+			name:  "Empty chunk",
+			codec: zstdCodec,
+			raw:   []byte{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			framed, err := EncodeChunk(tc.codec, tc.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := DecodeChunk(framed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(decoded, tc.raw) {
+				t.Errorf("decoded chunk did not match original. got %v, want %v", decoded, tc.raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package chunks
+
+import (
+	"sort"
+	"sync"
+)
+
+const (
+	defaultPredictorWindow    = 8
+	defaultPredictorLookahead = 4
+)
+
+// Predictor observes the sequence of chunk offsets a guest actually faults
+// in and suggests, highest-confidence first, which not-yet-local chunks
+// should be prioritized as a result. Callers with workload-specific access
+// patterns (VM boot, DB warmup) can implement this to plug their own policy
+// into a Puller via WithPredictor.
+type Predictor interface {
+	Observe(offset int64) []int64
+}
+
+// AdaptivePredictor is the default Predictor used by a Puller. It keeps a
+// small sliding window of recently faulted offsets to detect sequential
+// runs of a consistent stride, and falls back to a Markov-style predictor
+// keyed on the previously faulted chunk for workloads that don't access
+// memory sequentially.
+type AdaptivePredictor struct {
+	chunkSize int64
+
+	// mu guards every field below, since Observe is fed from a Puller's
+	// RecordAccess, which in turn is called directly from concurrent guest
+	// fault handlers (see ArbitraryReadWriterAt.OnAccess).
+	mu sync.Mutex
+
+	window []int64
+
+	windowSize int
+	lookahead  int
+
+	havePrev bool
+	prev     int64
+
+	transitions map[int64]map[int64]int
+}
+
+func NewAdaptivePredictor(chunkSize int64) *AdaptivePredictor {
+	return &AdaptivePredictor{
+		chunkSize: chunkSize,
+
+		windowSize: defaultPredictorWindow,
+		lookahead:  defaultPredictorLookahead,
+
+		transitions: map[int64]map[int64]int{},
+	}
+}
+
+func (p *AdaptivePredictor) Observe(offset int64) []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.havePrev {
+		counts, ok := p.transitions[p.prev]
+		if !ok {
+			counts = map[int64]int{}
+			p.transitions[p.prev] = counts
+		}
+		counts[offset]++
+	}
+	p.prev = offset
+	p.havePrev = true
+
+	p.window = append(p.window, offset)
+	if len(p.window) > p.windowSize {
+		p.window = p.window[1:]
+	}
+
+	if stride, ok := p.detectStride(); ok {
+		predictions := make([]int64, 0, p.lookahead)
+		for i := 1; i <= p.lookahead; i++ {
+			predictions = append(predictions, offset+int64(i)*stride)
+		}
+
+		return predictions
+	}
+
+	return p.predictMarkov(offset)
+}
+
+// detectStride reports the constant stride between consecutive offsets in
+// the sliding window, if there is one.
+func (p *AdaptivePredictor) detectStride() (int64, bool) {
+	if len(p.window) < 3 {
+		return 0, false
+	}
+
+	stride := p.window[1] - p.window[0]
+	if stride == 0 {
+		return 0, false
+	}
+
+	for i := 1; i < len(p.window)-1; i++ {
+		if p.window[i+1]-p.window[i] != stride {
+			return 0, false
+		}
+	}
+
+	return stride, true
+}
+
+// predictMarkov returns the offsets most often faulted in right after
+// offset historically, ordered by how often that transition was observed.
+func (p *AdaptivePredictor) predictMarkov(offset int64) []int64 {
+	counts, ok := p.transitions[offset]
+	if !ok || len(counts) == 0 {
+		return nil
+	}
+
+	type candidate struct {
+		offset int64
+		count  int
+	}
+
+	candidates := make([]candidate, 0, len(counts))
+	for off, count := range counts {
+		candidates = append(candidates, candidate{off, count})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].count != candidates[j].count {
+			return candidates[i].count > candidates[j].count
+		}
+
+		return candidates[i].offset < candidates[j].offset
+	})
+
+	if len(candidates) > p.lookahead {
+		candidates = candidates[:p.lookahead]
+	}
+
+	predictions := make([]int64, len(candidates))
+	for i, c := range candidates {
+		predictions[i] = c.offset
+	}
+
+	return predictions
+}
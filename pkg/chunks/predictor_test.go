@@ -0,0 +1,71 @@
+package chunks
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestAdaptivePredictorSequentialRun(t *testing.T) {
+	p := NewAdaptivePredictor(4)
+
+	for _, off := range []int64{0, 4, 8} {
+		p.Observe(off)
+	}
+
+	got := p.Observe(12)
+	want := []int64{16, 20, 24, 28}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("predictions did not match expected. got %v, want %v", got, want)
+	}
+}
+
+func TestAdaptivePredictorMarkovFallback(t *testing.T) {
+	p := NewAdaptivePredictor(4)
+
+	// Train the predictor on a repeated, non-sequential access pattern.
+	for i := 0; i < 3; i++ {
+		for _, off := range []int64{0, 40, 8} {
+			p.Observe(off)
+		}
+	}
+
+	got := p.Observe(0)
+	want := []int64{40}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("predictions did not match expected. got %v, want %v", got, want)
+	}
+}
+
+func TestAdaptivePredictorUnknownOffset(t *testing.T) {
+	p := NewAdaptivePredictor(4)
+
+	if got := p.Observe(0); got != nil {
+		t.Errorf("predictions did not match expected. got %v, want nil", got)
+	}
+}
+
+// TestAdaptivePredictorConcurrentObserve guards against a predictor used as
+// a Puller's shared state, fed from multiple concurrent guest fault
+// handlers (see Puller.RecordAccess), corrupting its internal map under
+// -race.
+func TestAdaptivePredictorConcurrentObserve(t *testing.T) {
+	p := NewAdaptivePredictor(4)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				p.Observe(int64(g*4 + i*4))
+			}
+		}(g)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,302 @@
+package chunks
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// finalizePriority is used for offsets passed to FinalizePull so that they
+// are always drained ahead of anything seeded from the caller-supplied
+// pullPriority function or boosted by a Predictor.
+const finalizePriority = int64(1) << 62
+
+// predictorBoostBase is the priority assigned to the highest-confidence
+// offset a Predictor returns from Observe, so that predicted chunks are
+// always drained ahead of the static pullPriority baseline. Lower-confidence
+// predictions (further down the slice Observe returns) get a slightly lower
+// priority, preserving their relative order.
+const predictorBoostBase = int64(1) << 48
+
+type pullJob struct {
+	priority int64
+	sequence int64
+	offset   int64
+
+	// index is maintained by pullQueue so that an already-queued job's
+	// priority can be raised in place (see Puller.RecordAccess) via
+	// heap.Fix instead of a linear scan.
+	index int
+}
+
+// pullQueue is a priority queue ordered by descending priority, falling
+// back to insertion order (sequence) so that pulling stays deterministic
+// for equal priorities.
+type pullQueue []*pullJob
+
+func (q pullQueue) Len() int { return len(q) }
+
+func (q pullQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+
+	return q[i].sequence < q[j].sequence
+}
+
+func (q pullQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *pullQueue) Push(x any) {
+	job := x.(*pullJob)
+	job.index = len(*q)
+	*q = append(*q, job)
+}
+
+func (q *pullQueue) Pop() any {
+	old := *q
+	n := len(old)
+	job := old[n-1]
+	old[n-1] = nil
+	job.index = -1
+	*q = old[:n-1]
+
+	return job
+}
+
+// PullerOption configures optional behavior of a Puller that most callers
+// don't need to set explicitly.
+type PullerOption func(*Puller)
+
+// WithPredictor overrides the Predictor a Puller uses to adapt its pull
+// order to the guest's actual access pattern, as observed via RecordAccess.
+// Without this option, a Puller defaults to an AdaptivePredictor.
+func WithPredictor(predictor Predictor) PullerOption {
+	return func(p *Puller) {
+		p.predictor = predictor
+	}
+}
+
+// Puller eagerly pulls every chunk of a SyncedReadWriterAt from remote into
+// local using a configurable number of workers. The pull queue is seeded by
+// a caller-supplied priority function, then continually re-prioritized by a
+// Predictor as RecordAccess reports the offsets the guest actually faults
+// in, so that chunks the workload is about to need jump ahead of the static
+// baseline order.
+type Puller struct {
+	ctx context.Context
+
+	srw        *SyncedReadWriterAt
+	chunkSize  int64
+	chunkCount int64
+
+	pullPriority func(offset int64) int64
+	predictor    Predictor
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queue     pullQueue
+	pending   map[int64]*pullJob
+	sequence  int64
+	finalized bool
+
+	wg   sync.WaitGroup
+	errs chan error
+	done chan struct{}
+}
+
+func NewPuller(
+	ctx context.Context,
+
+	srw *SyncedReadWriterAt,
+	chunkSize int64,
+	chunkCount int64,
+
+	pullPriority func(offset int64) int64,
+
+	opts ...PullerOption,
+) *Puller {
+	p := &Puller{
+		ctx: ctx,
+
+		srw:        srw,
+		chunkSize:  chunkSize,
+		chunkCount: chunkCount,
+
+		pullPriority: pullPriority,
+		predictor:    NewAdaptivePredictor(chunkSize),
+
+		errs: make(chan error),
+		done: make(chan struct{}),
+	}
+	p.cond = sync.NewCond(&p.mu)
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+func (p *Puller) Open(workers int64) error {
+	p.mu.Lock()
+	p.queue = make(pullQueue, 0, p.chunkCount)
+	p.pending = make(map[int64]*pullJob, p.chunkCount)
+	heap.Init(&p.queue)
+	for i := int64(0); i < p.chunkCount; i++ {
+		off := i * p.chunkSize
+
+		job := &pullJob{
+			priority: p.pullPriority(off),
+			sequence: p.sequence,
+			offset:   off,
+		}
+		p.sequence++
+
+		heap.Push(&p.queue, job)
+		p.pending[off] = job
+	}
+	p.mu.Unlock()
+
+	for i := int64(0); i < workers; i++ {
+		p.wg.Add(1)
+
+		go func() {
+			defer p.wg.Done()
+
+			p.work()
+		}()
+	}
+
+	go func() {
+		p.wg.Wait()
+
+		close(p.done)
+	}()
+
+	return nil
+}
+
+func (p *Puller) work() {
+	for {
+		job, ok := p.next()
+		if !ok {
+			return
+		}
+
+		if p.ctx.Err() != nil {
+			return
+		}
+
+		buf := make([]byte, p.chunkSize)
+		if _, err := p.srw.ReadAt(buf, job.offset); err != nil {
+			select {
+			case p.errs <- err:
+			case <-p.done:
+			}
+
+			return
+		}
+	}
+}
+
+func (p *Puller) next() (*pullJob, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.queue.Len() == 0 {
+		if p.finalized {
+			return nil, false
+		}
+
+		p.cond.Wait()
+	}
+
+	job := heap.Pop(&p.queue).(*pullJob)
+	delete(p.pending, job.offset)
+
+	return job, true
+}
+
+// RecordAccess reports that offset was the most recently faulted-in chunk,
+// feeding it to the Puller's Predictor and re-prioritizing whichever
+// not-yet-pulled offsets it predicts come next, while workers are still
+// draining the queue. The queue is left unchanged if the Predictor returns
+// no predictions; predicted offsets that have already been pulled, or that
+// FinalizePull has already queued at its higher priority, are skipped.
+func (p *Puller) RecordAccess(offset int64) {
+	if p.predictor == nil {
+		return
+	}
+
+	predicted := p.predictor.Observe(offset)
+	if len(predicted) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, off := range predicted {
+		job, ok := p.pending[off]
+		if !ok || job.priority == finalizePriority {
+			continue
+		}
+
+		job.priority = predictorBoostBase - int64(i)
+		heap.Fix(&p.queue, job.index)
+	}
+
+	p.cond.Broadcast()
+}
+
+// FinalizePull enqueues offsets (e.g. chunks reported dirty by the source of
+// a migration) for an immediate, high-priority pull, and signals that no
+// further offsets will be added once the queue has drained.
+func (p *Puller) FinalizePull(offsets []int64) {
+	p.mu.Lock()
+	for _, off := range offsets {
+		if job, ok := p.pending[off]; ok {
+			job.priority = finalizePriority
+			heap.Fix(&p.queue, job.index)
+
+			continue
+		}
+
+		job := &pullJob{
+			priority: finalizePriority,
+			sequence: p.sequence,
+			offset:   off,
+		}
+		p.sequence++
+
+		heap.Push(&p.queue, job)
+		p.pending[off] = job
+	}
+	p.finalized = true
+	p.mu.Unlock()
+
+	p.cond.Broadcast()
+}
+
+func (p *Puller) Wait() error {
+	select {
+	case err := <-p.errs:
+		return err
+	case <-p.done:
+		return nil
+	}
+}
+
+func (p *Puller) Close() error {
+	p.mu.Lock()
+	p.finalized = true
+	p.mu.Unlock()
+
+	p.cond.Broadcast()
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package chunks
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+)
+
+// TestPullerConcurrentRecordAccess exercises RecordAccess from multiple
+// goroutines at once, the way concurrent guest fault handlers (see
+// ArbitraryReadWriterAt.OnAccess) drive it in practice, and makes sure the
+// pull still completes cleanly under -race.
+func TestPullerConcurrentRecordAccess(t *testing.T) {
+	const chunkSize = 4
+	const chunkCount = 64
+
+	remoteFile, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(remoteFile.Name())
+
+	if err := remoteFile.Truncate(chunkSize * chunkCount); err != nil {
+		t.Fatal(err)
+	}
+
+	localFile, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(localFile.Name())
+
+	if err := localFile.Truncate(chunkSize * chunkCount); err != nil {
+		t.Fatal(err)
+	}
+
+	remote := NewChunkedReadWriterAt(remoteFile, chunkSize, chunkCount)
+	local := NewChunkedReadWriterAt(localFile, chunkSize, chunkCount)
+
+	srw := NewSyncedReadWriterAt(remote, local, func(off int64) error {
+		return nil
+	})
+
+	puller := NewPuller(
+		context.Background(),
+		srw,
+		chunkSize,
+		chunkCount,
+		func(offset int64) int64 {
+			return 1
+		},
+	)
+
+	if err := puller.Open(4); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			for i := int64(0); i < chunkCount; i++ {
+				puller.RecordAccess((int64(g) + i) % chunkCount * chunkSize)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// Signals that no further offsets will be enqueued, without marking any
+	// extra ones dirty, so Wait below doesn't block forever once the queue
+	// drains - every case in puller_test.go does the same for the same
+	// reason.
+	puller.FinalizePull(nil)
+
+	if err := puller.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := puller.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,161 @@
+package chunks
+
+import (
+	"io"
+	"sync"
+)
+
+// SyncedReadWriterAt serves reads from local if a chunk has already been
+// pulled, and transparently pulls it from remote (writing it into local
+// along the way) otherwise. Writes always go to local directly, marking the
+// chunk as local so that it is never overwritten by a pull again.
+type SyncedReadWriterAt struct {
+	remote io.ReaderAt
+	local  *ChunkedReadWriterAt
+
+	onChunkIsLocal func(off int64) error
+
+	codec Codec
+
+	mu      sync.Mutex
+	isLocal map[int64]struct{}
+}
+
+// SyncedReadWriterAtOption configures optional behavior of a
+// SyncedReadWriterAt that most callers don't need to set explicitly.
+type SyncedReadWriterAtOption func(*SyncedReadWriterAt)
+
+// WithCodec makes the SyncedReadWriterAt expect every chunk pulled from
+// remote to be framed with EncodeChunk, decoding it with codec (which may
+// differ per chunk, since the codec ID travels in the frame header) before
+// writing it into local. Without this option, remote is assumed to return
+// raw, unframed chunk bytes, preserving compatibility with pullers that
+// never negotiated compression.
+func WithCodec(codec Codec) SyncedReadWriterAtOption {
+	return func(s *SyncedReadWriterAt) {
+		s.codec = codec
+	}
+}
+
+func NewSyncedReadWriterAt(
+	remote io.ReaderAt,
+	local *ChunkedReadWriterAt,
+
+	onChunkIsLocal func(off int64) error,
+
+	opts ...SyncedReadWriterAtOption,
+) *SyncedReadWriterAt {
+	s := &SyncedReadWriterAt{
+		remote: remote,
+		local:  local,
+
+		onChunkIsLocal: onChunkIsLocal,
+
+		isLocal: map[int64]struct{}{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *SyncedReadWriterAt) chunkOffset(off int64) int64 {
+	size := s.local.ChunkSize()
+
+	return (off / size) * size
+}
+
+// IsLocal returns whether the chunk at off has already been pulled (or
+// written to directly) and so can be served from local without a pull.
+func (s *SyncedReadWriterAt) IsLocal(off int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.isLocal[s.chunkOffset(off)]
+
+	return ok
+}
+
+func (s *SyncedReadWriterAt) markLocal(off int64) {
+	s.mu.Lock()
+	s.isLocal[s.chunkOffset(off)] = struct{}{}
+	s.mu.Unlock()
+}
+
+// MarkAsRemote un-marks the chunks at offsets as local, so that the next
+// read pulls a fresh copy from remote. This is used once the caller knows
+// that remote has since diverged from what was pulled, e.g. because the
+// source of a live migration reported those offsets as dirty.
+func (s *SyncedReadWriterAt) MarkAsRemote(offsets []int64) {
+	s.mu.Lock()
+	for _, off := range offsets {
+		delete(s.isLocal, s.chunkOffset(off))
+	}
+	s.mu.Unlock()
+}
+
+func (s *SyncedReadWriterAt) ReadAt(p []byte, off int64) (int, error) {
+	chunkOff := s.chunkOffset(off)
+
+	if !s.IsLocal(chunkOff) {
+		if err := s.pull(chunkOff); err != nil {
+			return 0, err
+		}
+	}
+
+	return s.local.ReadAt(p, off)
+}
+
+func (s *SyncedReadWriterAt) pull(chunkOff int64) error {
+	size := s.local.ChunkSize()
+
+	raw := make([]byte, size)
+
+	if s.codec == nil {
+		if _, err := s.remote.ReadAt(raw, chunkOff); err != nil && err != io.EOF {
+			return err
+		}
+	} else {
+		// A framed chunk carries a header in addition to its (possibly
+		// compressed) payload, so remote may legitimately return fewer
+		// bytes than the buffer's capacity.
+		framed := make([]byte, size+chunkHeaderLen)
+
+		n, err := s.remote.ReadAt(framed, chunkOff)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		decoded, err := DecodeChunk(framed[:n])
+		if err != nil {
+			return err
+		}
+
+		copy(raw, decoded)
+	}
+
+	if _, err := s.local.WriteAt(raw, chunkOff); err != nil {
+		return err
+	}
+
+	s.markLocal(chunkOff)
+
+	if s.onChunkIsLocal != nil {
+		return s.onChunkIsLocal(chunkOff)
+	}
+
+	return nil
+}
+
+func (s *SyncedReadWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := s.local.WriteAt(p, off)
+	if err != nil {
+		return n, err
+	}
+
+	s.markLocal(s.chunkOffset(off))
+
+	return n, nil
+}
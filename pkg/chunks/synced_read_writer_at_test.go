@@ -0,0 +1,70 @@
+package chunks
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// framingRemote simulates a seeder configured with services.WithCodec: it
+// frames every chunk it serves with EncodeChunk, the same way
+// services.Seeder.ReadAt does once a codec is set.
+type framingRemote struct {
+	codec     Codec
+	chunkSize int64
+	data      []byte
+}
+
+func (f *framingRemote) ReadAt(p []byte, off int64) (int, error) {
+	raw := make([]byte, f.chunkSize)
+	copy(raw, f.data[off:off+f.chunkSize])
+
+	framed, err := EncodeChunk(f.codec, raw)
+	if err != nil {
+		return 0, err
+	}
+
+	return copy(p, framed), nil
+}
+
+func TestSyncedReadWriterAtPullsFramedChunks(t *testing.T) {
+	const chunkSize = 4
+	const chunkCount = 2
+
+	codec, err := NewCodec(CodecZstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("abcdwxyz")
+	remote := &framingRemote{codec: codec, chunkSize: chunkSize, data: data}
+
+	localFile, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(localFile.Name())
+
+	if err := localFile.Truncate(chunkSize * chunkCount); err != nil {
+		t.Fatal(err)
+	}
+
+	local := NewChunkedReadWriterAt(localFile, chunkSize, chunkCount)
+
+	synced := NewSyncedReadWriterAt(remote, local, nil, WithCodec(codec))
+
+	out := make([]byte, len(data))
+	for i := int64(0); i < chunkCount; i++ {
+		if _, err := synced.ReadAt(out[i*chunkSize:(i+1)*chunkSize], i*chunkSize); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !bytes.Equal(out, data) {
+		t.Errorf("data read did not match expected. got %v, want %v", out, data)
+	}
+
+	if !synced.IsLocal(0) || !synced.IsLocal(chunkSize) {
+		t.Error("expected both chunks to be marked local after pulling")
+	}
+}
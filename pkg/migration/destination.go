@@ -21,6 +21,45 @@ type Options struct {
 	PullWorkers int64
 
 	Verbose bool
+
+	// CompressionCodec selects the codec used to decompress chunks pulled
+	// from remote, overriding whatever Open's codec negotiation (see
+	// codecNegotiator) would otherwise have picked. Valid values are ""
+	// (the default - negotiate if remote supports it, else assume no
+	// compression), "zstd" and "s2".
+	CompressionCodec string
+
+	// Predictor overrides the Puller's adaptive scheduler, letting callers
+	// with workload-specific knowledge (VM boot, DB warmup) prioritize the
+	// remaining pull queue their own way. Defaults to a
+	// chunks.NewAdaptivePredictor if left nil.
+	Predictor chunks.Predictor
+}
+
+func (o *Options) predictor(chunkSize int64) chunks.Predictor {
+	if o.Predictor != nil {
+		return o.Predictor
+	}
+
+	return chunks.NewAdaptivePredictor(chunkSize)
+}
+
+func (o *Options) compressionCodec() (chunks.Codec, error) {
+	return chunks.CodecByName(o.CompressionCodec)
+}
+
+// codecNegotiator is implemented by remotes (e.g.
+// *services.BackendRemoteJSONRPC) that can report which chunks.CodecID they
+// frame chunks with. Destination.Open uses this to configure a matching
+// codec automatically when CompressionCodec is left unset, instead of
+// requiring both sides to be told the same codec name out of band - get it
+// wrong and SyncedReadWriterAt.pull would silently write the framing header
+// and compressed bytes into local storage as if they were the plaintext
+// chunk. Remotes that don't implement it (including every old one) are
+// unaffected: Destination falls back to assuming no compression, exactly
+// like before this existed.
+type codecNegotiator interface {
+	NegotiateCodec() (chunks.CodecID, error)
 }
 
 type Hooks struct {
@@ -130,6 +169,26 @@ func (m *Destination) Open() (string, error) {
 
 	local := chunks.NewChunkedReadWriterAt(m.local, m.options.ChunkSize, chunkCount)
 
+	codec, err := m.options.compressionCodec()
+	if err != nil {
+		return "", err
+	}
+
+	if codec == nil {
+		if negotiator, ok := m.remote.(codecNegotiator); ok {
+			id, err := negotiator.NegotiateCodec()
+			if err != nil {
+				return "", err
+			}
+
+			if id != chunks.CodecRaw {
+				if codec, err = chunks.NewCodec(id); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+
 	hook := m.hooks.OnChunkIsLocal
 	m.syncedReadWriter = chunks.NewSyncedReadWriterAt(m.remote, local, func(off int64) error {
 		if hook != nil {
@@ -137,7 +196,7 @@ func (m *Destination) Open() (string, error) {
 		}
 
 		return nil
-	})
+	}, chunks.WithCodec(codec))
 
 	m.puller = chunks.NewPuller(
 		m.ctx,
@@ -147,6 +206,7 @@ func (m *Destination) Open() (string, error) {
 		func(offset int64) int64 {
 			return 1
 		},
+		chunks.WithPredictor(m.options.predictor(m.options.ChunkSize)),
 	)
 
 	m.wg.Add(1)
@@ -165,6 +225,7 @@ func (m *Destination) Open() (string, error) {
 	}
 
 	arbitraryReadWriter := chunks.NewArbitraryReadWriterAt(m.syncedReadWriter, m.options.ChunkSize)
+	arbitraryReadWriter.OnAccess(m.puller.RecordAccess)
 
 	m.syncer = bbackend.NewReaderAtBackend(
 		arbitraryReadWriter,
@@ -250,4 +311,4 @@ func (m *Destination) Close() error {
 
 func (m *Destination) Sync() error {
 	return m.syncer.Sync()
-}
\ No newline at end of file
+}
@@ -0,0 +1,522 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pojntfx/r3map/pkg/chunks"
+)
+
+// jsonrpcVersion is the only version this transport speaks.
+const jsonrpcVersion = "2.0"
+
+// maxReadAtLength bounds a ReadAt request's length, since params.Length
+// comes straight from the wire and would otherwise let a client crash the
+// process with a single oversized or negative request (make([]byte, ...)
+// panics instead of returning an error for either).
+const maxReadAtLength = 64 * 1024 * 1024
+
+// maxWriteAtLength bounds a WriteAt request's Data, for the same reason
+// maxReadAtLength bounds ReadAt's Length: both come straight off the wire
+// before any chunk-size-aware caller gets a chance to reject them.
+const maxWriteAtLength = 64 * 1024 * 1024
+
+// maxMessageSize bounds how large a single line (TCP) or WebSocket message
+// this transport will read, so a client can't force it to buffer an
+// unbounded amount of memory before handleLine/dispatch ever get a chance
+// to apply maxReadAtLength/maxWriteAtLength.
+const maxMessageSize = 65 * 1024 * 1024
+
+type jsonrpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      int64           `json:"id"`
+}
+
+type jsonrpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type jsonrpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonrpcError   `json:"error,omitempty"`
+	ID      int64           `json:"id"`
+}
+
+type readAtParams struct {
+	Offset int64 `json:"offset"`
+	Length int   `json:"length"`
+}
+
+type readAtResult struct {
+	Data []byte `json:"data"`
+	N    int    `json:"n"`
+}
+
+type writeAtParams struct {
+	Offset int64  `json:"offset"`
+	Data   []byte `json:"data"`
+}
+
+type writeAtResult struct {
+	N int `json:"n"`
+}
+
+type sizeResult struct {
+	Size int64 `json:"size"`
+}
+
+type negotiateResult struct {
+	Codec byte `json:"codec"`
+}
+
+// SeederJSONRPC serves a Seeder over the standard, line-delimited JSON-RPC
+// 2.0 framing, over both plain TCP and WebSocket connections. Unlike the
+// gRPC, dRPC and fRPC transports, it needs no code generation, so that
+// non-Go clients (browsers, scripting languages) can participate as
+// pullers/seeders without a matching toolchain.
+type SeederJSONRPC struct {
+	svc *Seeder
+
+	tcpListener net.Listener
+	httpServer  *http.Server
+	upgrader    websocket.Upgrader
+
+	wg   sync.WaitGroup
+	errs chan error
+}
+
+func NewSeederJSONRPC(svc *Seeder) *SeederJSONRPC {
+	return &SeederJSONRPC{
+		svc:  svc,
+		errs: make(chan error),
+	}
+}
+
+// ListenTCP starts serving line-delimited JSON-RPC 2.0 requests on laddr.
+func (s *SeederJSONRPC) ListenTCP(laddr string) error {
+	lis, err := net.Listen("tcp", laddr)
+	if err != nil {
+		return err
+	}
+	s.tcpListener = lis
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+
+			go s.serveConn(conn)
+		}
+	}()
+
+	return nil
+}
+
+// ListenWebSocket starts serving JSON-RPC 2.0 requests framed as WebSocket
+// text messages on laddr.
+func (s *SeederJSONRPC) ListenWebSocket(laddr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := s.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		conn.SetReadLimit(maxMessageSize)
+
+		s.serveWebSocketConn(conn)
+	})
+
+	s.httpServer = &http.Server{Addr: laddr, Handler: mux}
+
+	lis, err := net.Listen("tcp", laddr)
+	if err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		if err := s.httpServer.Serve(lis); err != nil && err != http.ErrServerClosed {
+			s.errs <- err
+		}
+	}()
+
+	return nil
+}
+
+func (s *SeederJSONRPC) serveConn(conn net.Conn) {
+	defer conn.Close()
+	// A malformed or malicious request shouldn't be able to take the whole
+	// process down; isolate a panic to this connection.
+	defer func() { _ = recover() }()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+
+	enc := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		s.handleLine(scanner.Bytes(), enc.Encode)
+	}
+}
+
+func (s *SeederJSONRPC) serveWebSocketConn(conn *websocket.Conn) {
+	defer conn.Close()
+	// A malformed or malicious request shouldn't be able to take the whole
+	// process down; isolate a panic to this connection.
+	defer func() { _ = recover() }()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		s.handleLine(msg, func(v any) error {
+			return conn.WriteJSON(v)
+		})
+	}
+}
+
+// handleLine decodes either a single request or a batch (a JSON array of
+// requests), so that a puller can coalesce a burst of prefetch reads into
+// one round trip.
+func (s *SeederJSONRPC) handleLine(line []byte, write func(v any) error) {
+	trimmed := firstNonSpace(line)
+	if trimmed == '[' {
+		var reqs []jsonrpcRequest
+		if err := json.Unmarshal(line, &reqs); err != nil {
+			return
+		}
+
+		resps := make([]jsonrpcResponse, len(reqs))
+		for i, req := range reqs {
+			resps[i] = s.handleRequest(req)
+		}
+
+		_ = write(resps)
+
+		return
+	}
+
+	var req jsonrpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return
+	}
+
+	_ = write(s.handleRequest(req))
+}
+
+func firstNonSpace(p []byte) byte {
+	for _, b := range p {
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			return b
+		}
+	}
+
+	return 0
+}
+
+func (s *SeederJSONRPC) handleRequest(req jsonrpcRequest) jsonrpcResponse {
+	resp := jsonrpcResponse{JSONRPC: jsonrpcVersion, ID: req.ID}
+
+	result, err := s.dispatch(req)
+	if err != nil {
+		resp.Error = &jsonrpcError{Code: -32000, Message: err.Error()}
+
+		return resp
+	}
+
+	resp.Result = result
+
+	return resp
+}
+
+func (s *SeederJSONRPC) dispatch(req jsonrpcRequest) (json.RawMessage, error) {
+	switch req.Method {
+	case "ReadAt":
+		var params readAtParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		if params.Length < 0 || params.Length > maxReadAtLength {
+			return nil, fmt.Errorf("invalid read length %v", params.Length)
+		}
+
+		buf := make([]byte, params.Length)
+		n, err := s.svc.ReadAt(buf, params.Offset)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(readAtResult{Data: buf[:n], N: n})
+	case "WriteAt":
+		var params writeAtParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		if len(params.Data) > maxWriteAtLength {
+			return nil, fmt.Errorf("invalid write length %v", len(params.Data))
+		}
+
+		n, err := s.svc.WriteAt(params.Data, params.Offset)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(writeAtResult{N: n})
+	case "Size":
+		size, err := s.svc.Size()
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(sizeResult{Size: size})
+	case "Sync":
+		if err := s.svc.Sync(); err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(struct{}{})
+	case "Negotiate":
+		return json.Marshal(negotiateResult{Codec: byte(s.svc.Negotiate())})
+	default:
+		return nil, fmt.Errorf("unknown method %q", req.Method)
+	}
+}
+
+func (s *SeederJSONRPC) Wait() error {
+	for err := range s.errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SeederJSONRPC) Close() error {
+	if s.tcpListener != nil {
+		_ = s.tcpListener.Close()
+	}
+
+	if s.httpServer != nil {
+		_ = s.httpServer.Close()
+	}
+
+	s.wg.Wait()
+
+	close(s.errs)
+
+	return nil
+}
+
+// BackendRemoteJSONRPC is a go-nbd backend.Backend implemented by dialing a
+// SeederJSONRPC over a plain TCP connection. Concurrent calls that arrive
+// within coalesceFor of the first pending one are merged into a single JSON-RPC
+// batch request, which keeps a puller's prefetch bursts from costing one
+// round trip per chunk.
+type BackendRemoteJSONRPC struct {
+	conn net.Conn
+	enc  *json.Encoder
+	dec  *json.Decoder
+
+	mu          sync.Mutex
+	nextID      int64
+	pending     map[int64]chan jsonrpcResponse
+	batch       []jsonrpcRequest
+	flushTimer  *time.Timer
+	coalesceFor time.Duration
+}
+
+func NewBackendRemoteJSONRPC(ctx context.Context, raddr string) (*BackendRemoteJSONRPC, error) {
+	conn, err := net.Dial("tcp", raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &BackendRemoteJSONRPC{
+		conn: conn,
+		enc:  json.NewEncoder(conn),
+		dec:  json.NewDecoder(conn),
+
+		pending:     map[int64]chan jsonrpcResponse{},
+		coalesceFor: time.Millisecond,
+	}
+
+	go b.readLoop()
+
+	go func() {
+		<-ctx.Done()
+
+		_ = b.conn.Close()
+	}()
+
+	return b, nil
+}
+
+func (b *BackendRemoteJSONRPC) readLoop() {
+	for {
+		// Decode into a RawMessage first: unlike decoding straight into
+		// []jsonrpcResponse or jsonrpcResponse, this always succeeds for a
+		// single well-formed JSON value regardless of its shape, without
+		// consuming the next value from the stream on a shape mismatch, so
+		// we can safely inspect it before picking the right target type.
+		var raw json.RawMessage
+		if err := b.dec.Decode(&raw); err != nil {
+			return
+		}
+
+		var resps []jsonrpcResponse
+		if firstNonSpace(raw) == '[' {
+			if err := json.Unmarshal(raw, &resps); err != nil {
+				continue
+			}
+		} else {
+			var single jsonrpcResponse
+			if err := json.Unmarshal(raw, &single); err != nil {
+				continue
+			}
+			resps = []jsonrpcResponse{single}
+		}
+
+		b.mu.Lock()
+		for _, resp := range resps {
+			if ch, ok := b.pending[resp.ID]; ok {
+				ch <- resp
+				delete(b.pending, resp.ID)
+			}
+		}
+		b.mu.Unlock()
+	}
+}
+
+func (b *BackendRemoteJSONRPC) call(method string, params any) (json.RawMessage, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan jsonrpcResponse, 1)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.pending[id] = ch
+	b.batch = append(b.batch, jsonrpcRequest{JSONRPC: jsonrpcVersion, Method: method, Params: raw, ID: id})
+
+	if b.flushTimer == nil {
+		b.flushTimer = time.AfterFunc(b.coalesceFor, b.flush)
+	}
+	b.mu.Unlock()
+
+	resp := <-ch
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%v", resp.Error.Message)
+	}
+
+	return resp.Result, nil
+}
+
+func (b *BackendRemoteJSONRPC) flush() {
+	b.mu.Lock()
+	batch := b.batch
+	b.batch = nil
+	b.flushTimer = nil
+	b.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	_ = b.enc.Encode(batch)
+}
+
+func (b *BackendRemoteJSONRPC) ReadAt(p []byte, off int64) (int, error) {
+	raw, err := b.call("ReadAt", readAtParams{Offset: off, Length: len(p)})
+	if err != nil {
+		return 0, err
+	}
+
+	var result readAtResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return 0, err
+	}
+
+	return copy(p, result.Data), nil
+}
+
+func (b *BackendRemoteJSONRPC) WriteAt(p []byte, off int64) (int, error) {
+	raw, err := b.call("WriteAt", writeAtParams{Offset: off, Data: p})
+	if err != nil {
+		return 0, err
+	}
+
+	var result writeAtResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return 0, err
+	}
+
+	return result.N, nil
+}
+
+func (b *BackendRemoteJSONRPC) Size() (int64, error) {
+	raw, err := b.call("Size", struct{}{})
+	if err != nil {
+		return 0, err
+	}
+
+	var result sizeResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return 0, err
+	}
+
+	return result.Size, nil
+}
+
+func (b *BackendRemoteJSONRPC) Sync() error {
+	_, err := b.call("Sync", struct{}{})
+
+	return err
+}
+
+// NegotiateCodec asks the remote Seeder which CodecID (CodecRaw if none) it
+// frames chunks with, so that the caller can configure a matching
+// chunks.SyncedReadWriterAt via chunks.WithCodec before pulling.
+func (b *BackendRemoteJSONRPC) NegotiateCodec() (chunks.CodecID, error) {
+	raw, err := b.call("Negotiate", struct{}{})
+	if err != nil {
+		return chunks.CodecRaw, err
+	}
+
+	var result negotiateResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return chunks.CodecRaw, err
+	}
+
+	return chunks.CodecID(result.Codec), nil
+}
+
+func (b *BackendRemoteJSONRPC) Close() error {
+	return b.conn.Close()
+}
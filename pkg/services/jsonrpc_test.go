@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/pojntfx/r3map/pkg/chunks"
+)
+
+// memoryBackend is the minimal backend.Backend fake needed to drive a
+// Seeder end to end over the wire.
+type memoryBackend struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func newMemoryBackend(size int64) *memoryBackend {
+	return &memoryBackend{data: make([]byte, size)}
+}
+
+func (b *memoryBackend) ReadAt(p []byte, off int64) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return copy(p, b.data[off:]), nil
+}
+
+func (b *memoryBackend) WriteAt(p []byte, off int64) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return copy(b.data[off:], p), nil
+}
+
+func (b *memoryBackend) Size() (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return int64(len(b.data)), nil
+}
+
+func (b *memoryBackend) Sync() error {
+	return nil
+}
+
+func newTestSeederJSONRPC(t *testing.T, opts ...SeederOption) (*SeederJSONRPC, *BackendRemoteJSONRPC) {
+	t.Helper()
+
+	svc := NewSeeder(newMemoryBackend(64), opts...)
+
+	server := NewSeederJSONRPC(svc)
+	if err := server.ListenTCP("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	client, err := NewBackendRemoteJSONRPC(ctx, server.tcpListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return server, client
+}
+
+func TestBackendRemoteJSONRPCRoundTrip(t *testing.T) {
+	_, client := newTestSeederJSONRPC(t)
+
+	if _, err := client.WriteAt([]byte("testdata"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]byte, 8)
+	if _, err := client.ReadAt(out, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(out, []byte("testdata")) {
+		t.Errorf("data read did not match expected. got %v, want %v", out, []byte("testdata"))
+	}
+
+	size, err := client.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != 64 {
+		t.Errorf("size did not match expected. got %v, want %v", size, 64)
+	}
+
+	if err := client.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	codec, err := client.NegotiateCodec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if codec != chunks.CodecRaw {
+		t.Errorf("negotiated codec did not match expected. got %v, want %v", codec, chunks.CodecRaw)
+	}
+}
+
+func TestBackendRemoteJSONRPCNegotiatesConfiguredCodec(t *testing.T) {
+	codec, err := chunks.NewCodec(chunks.CodecZstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, client := newTestSeederJSONRPC(t, WithCodec(codec, 64))
+
+	negotiated, err := client.NegotiateCodec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if negotiated != chunks.CodecZstd {
+		t.Errorf("negotiated codec did not match expected. got %v, want %v", negotiated, chunks.CodecZstd)
+	}
+}
+
+// TestBackendRemoteJSONRPCCoalescesConcurrentCalls fires a burst of
+// concurrent WriteAt/ReadAt pairs, so that calls landing within the same
+// coalesceFor window are batched into shared JSON-RPC array
+// requests/responses (each ReadAt follows its own WriteAt's reply, so the
+// writes and reads go out in separate batches) - exercising the same request-ID-keyed dispatch that
+// readLoop's decode-then-fallback bug (fixed in b0f4f9e) used to corrupt.
+func TestBackendRemoteJSONRPCCoalescesConcurrentCalls(t *testing.T) {
+	_, client := newTestSeederJSONRPC(t)
+
+	const n = 16
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			off := int64(i)
+			want := byte(i)
+
+			if _, err := client.WriteAt([]byte{want}, off); err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			got := make([]byte, 1)
+			if _, err := client.ReadAt(got, off); err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			if got[0] != want {
+				t.Errorf("byte at offset %v did not match expected. got %v, want %v", off, got[0], want)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
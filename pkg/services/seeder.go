@@ -0,0 +1,97 @@
+package services
+
+import (
+	"github.com/pojntfx/go-nbd/pkg/backend"
+	"github.com/pojntfx/r3map/pkg/chunks"
+)
+
+// Seeder exposes a backend.Backend over RPC. It is the common payload that
+// each transport-specific constructor (NewSeederGrpc, NewSeederDrpc,
+// NewSeederFrpc, NewSeederJSONRPC, ...) adapts to its own wire format.
+type Seeder struct {
+	backend backend.Backend
+
+	chunkSize int64
+	codec     chunks.Codec
+}
+
+// SeederOption configures optional behavior of a Seeder that most callers
+// don't need to set explicitly.
+type SeederOption func(*Seeder)
+
+// WithCodec makes the Seeder frame every chunk it serves with
+// chunks.EncodeChunk using codec, so that a puller configured with
+// chunks.WithCodec can decode what it pulls. chunkSize must match the size
+// the backend is chunked at; it is used to read a whole chunk out of
+// backend before framing it, since ReadAt requests may ask for the framed
+// buffer's full capacity rather than the raw chunk size. Without this
+// option, ReadAt forwards to backend unmodified, preserving the old,
+// headerless wire format.
+func WithCodec(codec chunks.Codec, chunkSize int64) SeederOption {
+	return func(s *Seeder) {
+		s.codec = codec
+		s.chunkSize = chunkSize
+	}
+}
+
+func NewSeeder(b backend.Backend, opts ...SeederOption) *Seeder {
+	s := &Seeder{backend: b}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Configure applies opts to an already-constructed Seeder. This is for
+// callers (like the migration-benchmark-server binary) that only get a
+// *Seeder back from a helper constructor and still need to turn
+// compression on afterwards, before any transport starts serving it.
+func (s *Seeder) Configure(opts ...SeederOption) {
+	for _, opt := range opts {
+		opt(s)
+	}
+}
+
+func (s *Seeder) ReadAt(p []byte, off int64) (int, error) {
+	if s.codec == nil {
+		return s.backend.ReadAt(p, off)
+	}
+
+	raw := make([]byte, s.chunkSize)
+	if _, err := s.backend.ReadAt(raw, off); err != nil {
+		return 0, err
+	}
+
+	framed, err := chunks.EncodeChunk(s.codec, raw)
+	if err != nil {
+		return 0, err
+	}
+
+	return copy(p, framed), nil
+}
+
+func (s *Seeder) WriteAt(p []byte, off int64) (int, error) {
+	return s.backend.WriteAt(p, off)
+}
+
+func (s *Seeder) Size() (int64, error) {
+	return s.backend.Size()
+}
+
+func (s *Seeder) Sync() error {
+	return s.backend.Sync()
+}
+
+// Negotiate reports the CodecID this Seeder frames chunks with (CodecRaw if
+// none was configured via WithCodec), so that a remote backend can discover
+// it and configure a matching SyncedReadWriterAt without the two sides
+// having to be told the same codec name out of band.
+func (s *Seeder) Negotiate() chunks.CodecID {
+	if s.codec == nil {
+		return chunks.CodecRaw
+	}
+
+	return s.codec.ID()
+}